@@ -19,12 +19,16 @@ package controllers
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
-	"k8s.io/apimachinery/pkg/api/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -42,6 +46,11 @@ const (
 	FluentdConfig        = "FluentdConfig"
 	ClusterFluentdConfig = "ClusterFluentdConfig"
 
+	// FluentdConfigFinalizer is installed on FluentdConfig/ClusterFluentdConfig objects so the
+	// controller gets a chance to drop their contribution from the rendered secret and garbage
+	// collect any loader-generated secrets before the object actually disappears.
+	FluentdConfigFinalizer = "fluentd.fluent.io/config-finalizer"
+
 	FluentdSecretMainKey   = "fluent.conf"
 	FluentdSecretSystemKey = "system.conf"
 	FluentdSecretAppKey    = "app.conf"
@@ -72,13 +81,54 @@ const (
 	</match>
 </label>
 `
+
+	// Condition types surfaced on FluentdConfig/ClusterFluentdConfig/Fluentd status.
+	ConditionReady           = "Ready"
+	ConditionConfigRendered  = "ConfigRendered"
+	ConditionSecretPublished = "SecretPublished"
+
+	// FluentdRPCPort is the rpc_endpoint port SYSTEM always enables.
+	FluentdRPCPort = 24444
+	// FluentdReloadPath is fluentd's built-in endpoint for re-reading its config in place.
+	FluentdReloadPath = "/api/config.gracefulReload"
+
+	// Fluentd.Spec.ReloadStrategy values.
+	ReloadStrategyGracefulReload = "GracefulReload"
+	ReloadStrategyRestart        = "Restart"
+	ReloadStrategyNone           = "None"
+
+	// reloadWorkerPoolSize bounds how many pods are reloaded concurrently per fd.
+	reloadWorkerPoolSize = 8
+	// reloadPodTimeout bounds how long a single pod's reload RPC is allowed to take.
+	reloadPodTimeout = 5 * time.Second
 )
 
+// cfgSummary tallies how many cfgs matched a fluentd and how many of those failed to bind
+// cleanly, so Reconcile can roll the totals up into the Fluentd's own status.
+type cfgSummary struct {
+	matched int
+	failed  int
+}
+
+// renderPass carries the state that accumulates across every clustercfg/cfg processed for a
+// single fd during one Reconcile pass: cfgs awaiting finalizer removal, the match/fail tally,
+// and the round-robin cursor used to spread unplaced cfgs across fd.Spec.Workers.
+type renderPass struct {
+	pendingFinalizerRemoval []client.Object
+	summary                 cfgSummary
+	nextWorker              int32
+	totalWorkers            int32
+}
+
 // FluentdConfigReconciler reconciles a FluentdConfig object
 type FluentdConfigReconciler struct {
 	client.Client
 	Log    logr.Logger
 	Scheme *runtime.Scheme
+
+	// HTTPClient issues the graceful-reload RPCs against fluentd pods; defaults to
+	// http.DefaultClient when nil.
+	HTTPClient *http.Client
 }
 
 //+kubebuilder:rbac:groups=fluentd.fluent.io,resources=fluentdconfigs,verbs=get;list;watch;create;update;patch;delete
@@ -89,6 +139,9 @@ type FluentdConfigReconciler struct {
 //+kubebuilder:rbac:groups=fluentd.fluent.io,resources=fluentds/status,verbs=patch
 //+kubebuilder:rbac:groups=fluentd.fluent.io,resources=fluentdconfigs/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=fluentd.fluent.io,resources=fluentdconfigs/finalizers,verbs=update
+//+kubebuilder:rbac:groups=fluentd.fluent.io,resources=clusterfluentdconfigs/finalizers,verbs=update
+//+kubebuilder:rbac:groups="",resources=pods,verbs=list;watch
+//+kubebuilder:rbac:groups=apps,resources=statefulsets,verbs=get;update;patch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -106,13 +159,31 @@ func (r *FluentdConfigReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 	var fluentdList fluentdv1alpha1.FluentdList
 
 	if err := r.List(ctx, &fluentdList); err != nil {
-		if errors.IsNotFound(err) {
+		if apierrors.IsNotFound(err) {
 			r.Log.V(1).Info("can not find fluentd CR definition.")
 			return ctrl.Result{Requeue: true, RequeueAfter: time.Duration(1)}, nil
 		}
 		return ctrl.Result{}, err
 	}
 
+	// A cfg being deleted is normally unstuck by the per-fd loop below, which only looks at cfgs
+	// still matched by some live fd's selector. A cfg whose owning fd was deleted first, or whose
+	// labels no longer match any live fd, would never be visited again - sweep those directly so
+	// kubectl delete on them doesn't hang in Terminating forever.
+	if err := r.sweepOrphanedCfgs(ctx, fluentdList.Items); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// pendingFinalizerRemoval (bundled into each fd's renderPass below) accumulates cfgs/
+	// clustercfgs that are being deleted and whose contribution has already been excluded from
+	// that fd's secret. Their finalizer is stripped right after that fd's re-render below has
+	// actually happened, so a crash between exclusion and removal just re-runs the cleanup on
+	// the next reconcile.
+
+	// Every fluentd is rebuilt from scratch on every reconcile, so a cfg whose labels changed
+	// and now matches a different fluentd is "adopted" for free: it lands in the new owner's
+	// secret here, and the old owner's secret simply stops carrying it since its selector no
+	// longer picks the cfg up.
 	for _, fd := range fluentdList.Items {
 		// Get the selector contained in this fluentd instance
 		fdSelector, err := metav1.LabelSelectorAsSelector(&fd.Spec.FluentdCfgSelector)
@@ -137,16 +208,6 @@ func (r *FluentdConfigReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 		// globalCfgLabels stores cfgLabels, the same cfg label is not allowed.
 		globalCfgLabels := make(map[string]bool)
 
-		// combine cluster cfgs
-		if err := r.ClusterCfgsForFluentd(ctx, fdSelector, sl, pgr, globalCfgLabels); err != nil {
-			return ctrl.Result{}, err
-		}
-
-		// combine namespaced cfgs
-		if err := r.CfgsForFluentd(ctx, fdSelector, sl, pgr, globalCfgLabels); err != nil {
-			return ctrl.Result{}, err
-		}
-
 		// Get fluentd workers
 		var workers int32 = 1
 		var enableMultiWorkers bool
@@ -158,6 +219,21 @@ func (r *FluentdConfigReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 			enableMultiWorkers = true
 		}
 
+		// pass threads the bits of state that accumulate across every clustercfg/cfg matched to
+		// this fd: pending finalizer removals, the match/fail tally, and the round-robin worker
+		// cursor used by WorkerPlacement.
+		pass := &renderPass{totalWorkers: workers}
+
+		// combine cluster cfgs
+		if err := r.ClusterCfgsForFluentd(ctx, fdSelector, sl, pgr, globalCfgLabels, pass); err != nil {
+			return ctrl.Result{}, err
+		}
+
+		// combine namespaced cfgs
+		if err := r.CfgsForFluentd(ctx, fdSelector, sl, pgr, globalCfgLabels, pass); err != nil {
+			return ctrl.Result{}, err
+		}
+
 		// Create or update the global main app secret of the fluentd instance in its namespace.
 		mainAppCfg, err := pgr.RenderMainConfig(enableMultiWorkers)
 		if err != nil {
@@ -173,7 +249,7 @@ func (r *FluentdConfigReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 			},
 		}
 
-		if _, err := controllerutil.CreateOrPatch(ctx, r.Client, sec, func() error {
+		secOp, err := controllerutil.CreateOrPatch(ctx, r.Client, sec, func() error {
 			sec.Data = map[string][]byte{
 				FluentdSecretMainKey:   []byte(FlUENT_INCLUDE),
 				FluentdSecretAppKey:    []byte(mainAppCfg),
@@ -186,12 +262,35 @@ func (r *FluentdConfigReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 				return err
 			}
 			return nil
-		}); err != nil {
+		})
+		if err != nil {
 			return ctrl.Result{}, err
 		}
 
 		r.Log.Info("Main configuration has updated", "logging-control-plane", fd.Namespace, "fd", fd.Name, "secret", secName)
 
+		// Only ask fluentd to re-read its config when the secret actually changed: there's no
+		// reason to make every pod hit the RPC endpoint on a no-op reconcile.
+		reload := reloadResult{strategy: ReloadStrategyNone}
+		if secOp != controllerutil.OperationResultNone {
+			reload, err = r.reloadFluentd(ctx, &fd)
+			if err != nil {
+				return ctrl.Result{}, err
+			}
+		}
+
+		if err := r.patchFluentdStatus(ctx, &fd, secName, pass.summary, reload); err != nil {
+			return ctrl.Result{}, err
+		}
+
+		// This fd's secret has now been re-rendered without the doomed cfgs, so it's safe to let
+		// them actually go away.
+		for _, obj := range pass.pendingFinalizerRemoval {
+			obj.SetFinalizers(removeString(obj.GetFinalizers(), FluentdConfigFinalizer))
+			if err := r.Update(ctx, obj); err != nil {
+				return ctrl.Result{}, err
+			}
+		}
 	}
 
 	return ctrl.Result{}, nil
@@ -200,12 +299,12 @@ func (r *FluentdConfigReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 // ClusterCfgsForFluentd combines all cluster cfgs selected by this fd
 func (r *FluentdConfigReconciler) ClusterCfgsForFluentd(
 	ctx context.Context, fdSelector labels.Selector, sl plugins.SecretLoader, pgr *fluentdv1alpha1.PluginResources,
-	globalCfgLabels map[string]bool) error {
+	globalCfgLabels map[string]bool, pass *renderPass) error {
 
 	var clustercfgs fluentdv1alpha1.ClusterFluentdConfigList
 	// Use fluentd selector to match the cluster config.
 	if err := r.List(ctx, &clustercfgs, client.MatchingLabelsSelector{Selector: fdSelector}); err != nil {
-		if errors.IsNotFound(err) {
+		if apierrors.IsNotFound(err) {
 			return nil
 		}
 		return err
@@ -213,7 +312,29 @@ func (r *FluentdConfigReconciler) ClusterCfgsForFluentd(
 
 	allNamespaces := make([]string, 0)
 
-	for _, cfg := range clustercfgs.Items {
+	for i := range clustercfgs.Items {
+		cfg := clustercfgs.Items[i]
+
+		// This cfg is on its way out: exclude it from this fd's router/secret entirely and
+		// garbage-collect anything the secret loader generated for it on its behalf. The
+		// finalizer itself is only dropped once this fd's secret has actually been re-rendered.
+		if cfg.GetDeletionTimestamp() != nil {
+			if containsString(cfg.GetFinalizers(), FluentdConfigFinalizer) {
+				if err := sl.CleanupCfgSecrets(ctx, cfg.GetCfgId()); err != nil {
+					return err
+				}
+				pass.pendingFinalizerRemoval = append(pass.pendingFinalizerRemoval, &clustercfgs.Items[i])
+			}
+			continue
+		}
+
+		if !containsString(cfg.GetFinalizers(), FluentdConfigFinalizer) {
+			cfg.SetFinalizers(append(cfg.GetFinalizers(), FluentdConfigFinalizer))
+			if err := r.Update(ctx, &cfg); err != nil {
+				return err
+			}
+		}
+
 		// If the field watchedNamespaces is empty, all namesapces will be watched.
 		watchedNamespaces := cfg.GetWatchedNamespaces()
 
@@ -232,6 +353,15 @@ func (r *FluentdConfigReconciler) ClusterCfgsForFluentd(
 			cfg.Spec.WatchedNamespaces = allNamespaces
 		}
 
+		// WatchedNamespaceSelector/ExcludedNamespaceSelector let a cfg track namespaces by label
+		// instead of enumerating them by name; resolve them against the explicit list above so a
+		// tenant namespace is picked up the moment it's created or relabeled, with no CR edit.
+		resolvedNamespaces, err := r.resolveWatchedNamespaces(ctx, cfg.Spec.WatchedNamespaces, cfg.Spec.WatchedNamespaceSelector, cfg.Spec.ExcludedNamespaceSelector)
+		if err != nil {
+			return err
+		}
+		cfg.Spec.WatchedNamespaces = resolvedNamespaces
+
 		// Build the inner router for this cfg.
 		// Each cfg is a workflow.
 		cfgRouter, err := pgr.BuildCfgRouter(&cfg)
@@ -251,19 +381,35 @@ func (r *FluentdConfigReconciler) ClusterCfgsForFluentd(
 			return err
 		}
 
+		// Combine the filter/output pluginstores in this fluentd config. resourceErrs carries the
+		// specific filter/output CR each failure came from, so it can be attributed to the right
+		// Binding instead of being collapsed into one joined string.
+		cfgResouces, resourceErrs := pgr.PatchAndFilterClusterLevelResources(sl, cfg.GetCfgId(), clusterfilters, clusteroutputs)
+
 		// The errors array patched to this cfg if this array is not empty.
-		errs := make([]string, 0)
+		errs := make([]string, 0, len(resourceErrs)+1)
+		for _, re := range resourceErrs {
+			errs = append(errs, re.Err.Error())
+		}
 
-		// Combine the filter/output pluginstores in this fluentd config.
-		cfgResouces, combinedErrs := pgr.PatchAndFilterClusterLevelResources(sl, cfg.GetCfgId(), clusterfilters, clusteroutputs)
-		pgr.WithCfgResources(cfgRouterLabel, cfgResouces)
-		errs = append(errs, combinedErrs...)
+		// Resolve which worker(s) this cfg's <source>/<match> blocks are wrapped in. An invalid
+		// pinned index still renders (round-robin takes over) so one bad cfg can't blank out the
+		// whole secret, but the mistake is reported the same way as any other binding failure.
+		assignedWorkers, werr := pass.nextWorkerAssignment(cfg.Spec.WorkerPlacement)
+		if werr != nil {
+			errs = append(errs, werr.Error())
+		}
+		pgr.WithCfgResources(cfgRouterLabel, cfgResouces, assignedWorkers)
 
+		bindings := bindResources(clusterfilters, clusteroutputs, nil, nil)
+
+		pass.summary.matched++
 		if len(errs) > 0 {
-			err = r.PatchObjectErrors(ctx, &cfg, strings.Join(errs, ","))
-			if err != nil {
-				return err
-			}
+			pass.summary.failed++
+		}
+
+		if err := r.PatchCfgStatus(ctx, &cfg, bindings, resourceErrs, errs); err != nil {
+			return err
 		}
 	}
 
@@ -272,18 +418,39 @@ func (r *FluentdConfigReconciler) ClusterCfgsForFluentd(
 
 // CfgsForFluentd combines all namespaced cfgs selected by this fd
 func (r *FluentdConfigReconciler) CfgsForFluentd(ctx context.Context, fdSelector labels.Selector, sl plugins.SecretLoader,
-	pgr *fluentdv1alpha1.PluginResources, globalCfgLabels map[string]bool) error {
+	pgr *fluentdv1alpha1.PluginResources, globalCfgLabels map[string]bool, pass *renderPass) error {
 
 	var cfgs fluentdv1alpha1.FluentdConfigList
 	// Use fluentd selector to match the namespaced configs.
 	if err := r.List(ctx, &cfgs, client.MatchingLabelsSelector{Selector: fdSelector}); err != nil {
-		if errors.IsNotFound(err) {
+		if apierrors.IsNotFound(err) {
 			return nil
 		}
 		return err
 	}
 
-	for _, cfg := range cfgs.Items {
+	for i := range cfgs.Items {
+		cfg := cfgs.Items[i]
+
+		// Same finalizer dance as ClusterCfgsForFluentd: a cfg being deleted is simply excluded
+		// from this pass, which already removes its contribution from the rendered secret.
+		if cfg.GetDeletionTimestamp() != nil {
+			if containsString(cfg.GetFinalizers(), FluentdConfigFinalizer) {
+				if err := sl.CleanupCfgSecrets(ctx, cfg.GetCfgId()); err != nil {
+					return err
+				}
+				pass.pendingFinalizerRemoval = append(pass.pendingFinalizerRemoval, &cfgs.Items[i])
+			}
+			continue
+		}
+
+		if !containsString(cfg.GetFinalizers(), FluentdConfigFinalizer) {
+			cfg.SetFinalizers(append(cfg.GetFinalizers(), FluentdConfigFinalizer))
+			if err := r.Update(ctx, &cfg); err != nil {
+				return err
+			}
+		}
+
 		// build the inner router for this cfg.
 		cfgRouter, err := pgr.BuildCfgRouter(&cfg)
 		if err != nil {
@@ -309,25 +476,43 @@ func (r *FluentdConfigReconciler) CfgsForFluentd(ctx context.Context, fdSelector
 			return err
 		}
 
-		// The errors array patched to this cfg if this array is not empty.
-		errs := make([]string, 0)
-
-		// Combine the cluster filter/output pluginstores in this fluentd config.
-		clustercfgResouces, cerrs := pgr.PatchAndFilterClusterLevelResources(sl, cfg.GetCfgId(), clusterfilters, clusteroutputs)
-		errs = append(errs, cerrs...)
+		// Combine the cluster filter/output pluginstores in this fluentd config. resourceErrs
+		// carries the specific filter/output CR each failure came from, so it can be attributed to
+		// the right Binding instead of being collapsed into one joined string.
+		clustercfgResouces, cResourceErrs := pgr.PatchAndFilterClusterLevelResources(sl, cfg.GetCfgId(), clusterfilters, clusteroutputs)
 
 		// Combine the namespaced filter/output pluginstores in this fluentd config.
-		cfgResouces, nerrs := pgr.PatchAndFilterNamespacedLevelResources(sl, cfg.GetCfgId(), filters, outputs)
+		cfgResouces, nResourceErrs := pgr.PatchAndFilterNamespacedLevelResources(sl, cfg.GetCfgId(), filters, outputs)
 		cfgResouces.FilterPlugins = append(cfgResouces.FilterPlugins, clustercfgResouces.FilterPlugins...)
 		cfgResouces.OutputPlugins = append(cfgResouces.OutputPlugins, clustercfgResouces.OutputPlugins...)
-		pgr.WithCfgResources(cfgRouterLabel, cfgResouces)
-		errs = append(errs, nerrs...)
 
+		resourceErrs := make([]fluentdv1alpha1.ResourceError, 0, len(cResourceErrs)+len(nResourceErrs))
+		resourceErrs = append(resourceErrs, cResourceErrs...)
+		resourceErrs = append(resourceErrs, nResourceErrs...)
+
+		// The errors array patched to this cfg if this array is not empty.
+		errs := make([]string, 0, len(resourceErrs)+1)
+		for _, re := range resourceErrs {
+			errs = append(errs, re.Err.Error())
+		}
+
+		// See ClusterCfgsForFluentd: an invalid WorkerPlacement still renders via round-robin, it
+		// just also surfaces as a binding failure.
+		assignedWorkers, werr := pass.nextWorkerAssignment(cfg.Spec.WorkerPlacement)
+		if werr != nil {
+			errs = append(errs, werr.Error())
+		}
+		pgr.WithCfgResources(cfgRouterLabel, cfgResouces, assignedWorkers)
+
+		bindings := bindResources(clusterfilters, clusteroutputs, filters, outputs)
+
+		pass.summary.matched++
 		if len(errs) > 0 {
-			err = r.PatchObjectErrors(ctx, &cfg, strings.Join(errs, ","))
-			if err != nil {
-				return err
-			}
+			pass.summary.failed++
+		}
+
+		if err := r.PatchCfgStatus(ctx, &cfg, bindings, resourceErrs, errs); err != nil {
+			return err
 		}
 	}
 
@@ -396,24 +581,559 @@ func (r *FluentdConfigReconciler) ListNamespacedLevelResources(ctx context.Conte
 	return filters.Items, outputs.Items, nil
 }
 
+// nextWorkerAssignment resolves a cfg's WorkerPlacement into the concrete worker indexes its
+// <source>/<match> blocks get wrapped in. A nil placement (or any other validation failure)
+// falls back to round-robin across [0, totalWorkers), advancing pass's shared cursor so
+// unplaced cfgs still spread out instead of piling onto worker 0.
+func (pass *renderPass) nextWorkerAssignment(placement *fluentdv1alpha1.WorkerPlacement) ([]int32, error) {
+	roundRobin := func() []int32 {
+		if pass.totalWorkers <= 1 {
+			return []int32{0}
+		}
+		w := pass.nextWorker % pass.totalWorkers
+		pass.nextWorker++
+		return []int32{w}
+	}
+
+	if placement == nil {
+		return roundRobin(), nil
+	}
+
+	if len(placement.Workers) > 0 {
+		for _, w := range placement.Workers {
+			if w < 0 || w >= pass.totalWorkers {
+				return roundRobin(), fmt.Errorf("pinned worker %d is out of range for %d worker(s)", w, pass.totalWorkers)
+			}
+		}
+		return placement.Workers, nil
+	}
+
+	if placement.Shard != nil {
+		replicas, ordinal := placement.Shard.Replicas, placement.Shard.Ordinal
+		if replicas <= 0 {
+			return roundRobin(), fmt.Errorf("worker shard replicas must be positive, got %d", replicas)
+		}
+		if ordinal < 0 || ordinal >= replicas {
+			return roundRobin(), fmt.Errorf("worker shard ordinal %d is out of range for %d replica(s)", ordinal, replicas)
+		}
+
+		assigned := make([]int32, 0, pass.totalWorkers/replicas+1)
+		for w := int32(0); w < pass.totalWorkers; w++ {
+			if w%replicas == ordinal {
+				assigned = append(assigned, w)
+			}
+		}
+		if len(assigned) == 0 {
+			return roundRobin(), fmt.Errorf("worker shard %d/%d matched no workers out of %d", ordinal, replicas, pass.totalWorkers)
+		}
+		return assigned, nil
+	}
+
+	return roundRobin(), nil
+}
+
+// resolveWatchedNamespaces intersects the explicit watchedNamespaces list with the namespaces
+// matching watchedSelector (when set), then subtracts the namespaces matching excludedSelector
+// (when set). A nil selector leaves the corresponding step a no-op.
+func (r *FluentdConfigReconciler) resolveWatchedNamespaces(ctx context.Context, watchedNamespaces []string,
+	watchedSelector, excludedSelector *metav1.LabelSelector) ([]string, error) {
+
+	if watchedSelector == nil && excludedSelector == nil {
+		return watchedNamespaces, nil
+	}
+
+	if watchedSelector != nil {
+		matched, err := r.listNamespaceNames(ctx, watchedSelector)
+		if err != nil {
+			return nil, err
+		}
+		matchedSet := make(map[string]bool, len(matched))
+		for _, ns := range matched {
+			matchedSet[ns] = true
+		}
+
+		filtered := make([]string, 0, len(watchedNamespaces))
+		for _, ns := range watchedNamespaces {
+			if matchedSet[ns] {
+				filtered = append(filtered, ns)
+			}
+		}
+		watchedNamespaces = filtered
+	}
+
+	if excludedSelector != nil {
+		excluded, err := r.listNamespaceNames(ctx, excludedSelector)
+		if err != nil {
+			return nil, err
+		}
+		excludedSet := make(map[string]bool, len(excluded))
+		for _, ns := range excluded {
+			excludedSet[ns] = true
+		}
+
+		filtered := make([]string, 0, len(watchedNamespaces))
+		for _, ns := range watchedNamespaces {
+			if !excludedSet[ns] {
+				filtered = append(filtered, ns)
+			}
+		}
+		watchedNamespaces = filtered
+	}
+
+	return watchedNamespaces, nil
+}
+
+// listNamespaceNames lists the names of the namespaces matching the given label selector.
+func (r *FluentdConfigReconciler) listNamespaceNames(ctx context.Context, labelSelector *metav1.LabelSelector) ([]string, error) {
+	selector, err := metav1.LabelSelectorAsSelector(labelSelector)
+	if err != nil {
+		return nil, err
+	}
+
+	var namespaceList corev1.NamespaceList
+	if err := r.List(ctx, &namespaceList, client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(namespaceList.Items))
+	for _, item := range namespaceList.Items {
+		names = append(names, item.Name)
+	}
+	return names, nil
+}
+
+// sweepOrphanedCfgs cleans up and unblocks any FluentdConfig/ClusterFluentdConfig that is
+// Terminating, still carries FluentdConfigFinalizer, and no longer matches any live fluentd's
+// selector. Such a cfg is never visited by ClusterCfgsForFluentd/CfgsForFluentd - both only look
+// at cfgs currently matched by a live fdSelector - so without this sweep it would keep its
+// finalizer forever once its owning fd is gone or its labels are changed away from every fd.
+func (r *FluentdConfigReconciler) sweepOrphanedCfgs(ctx context.Context, fluentds []fluentdv1alpha1.Fluentd) error {
+	liveSelectors := make([]labels.Selector, 0, len(fluentds))
+	namespaces := make([]string, 0, len(fluentds))
+	for _, fd := range fluentds {
+		if sel, err := metav1.LabelSelectorAsSelector(&fd.Spec.FluentdCfgSelector); err == nil {
+			liveSelectors = append(liveSelectors, sel)
+		}
+		namespaces = append(namespaces, fd.Namespace)
+	}
+
+	matchesLiveFd := func(set labels.Set) bool {
+		for _, sel := range liveSelectors {
+			if sel.Matches(set) {
+				return true
+			}
+		}
+		return false
+	}
+
+	var clustercfgs fluentdv1alpha1.ClusterFluentdConfigList
+	if err := r.List(ctx, &clustercfgs); err != nil {
+		return err
+	}
+	for i := range clustercfgs.Items {
+		cfg := &clustercfgs.Items[i]
+		if cfg.GetDeletionTimestamp() == nil || !containsString(cfg.GetFinalizers(), FluentdConfigFinalizer) || matchesLiveFd(cfg.GetLabels()) {
+			continue
+		}
+		if err := r.cleanupOrphanedCfg(ctx, cfg, cfg.GetCfgId(), namespaces); err != nil {
+			return err
+		}
+	}
+
+	var cfgs fluentdv1alpha1.FluentdConfigList
+	if err := r.List(ctx, &cfgs); err != nil {
+		return err
+	}
+	for i := range cfgs.Items {
+		cfg := &cfgs.Items[i]
+		if cfg.GetDeletionTimestamp() == nil || !containsString(cfg.GetFinalizers(), FluentdConfigFinalizer) || matchesLiveFd(cfg.GetLabels()) {
+			continue
+		}
+		if err := r.cleanupOrphanedCfg(ctx, cfg, cfg.GetCfgId(), namespaces); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// cleanupOrphanedCfg removes any secrets obj's cfgId generated in every fd namespace we know
+// about, then drops its finalizer. Unlike the per-fd cleanup path, there's no live secret left to
+// re-render around obj first - nothing references it any more - so it's safe to unblock it
+// immediately.
+func (r *FluentdConfigReconciler) cleanupOrphanedCfg(ctx context.Context, obj client.Object, cfgId string, namespaces []string) error {
+	for _, ns := range namespaces {
+		sl := plugins.NewSecretLoader(r.Client, ns, r.Log)
+		if err := sl.CleanupCfgSecrets(ctx, cfgId); err != nil {
+			return err
+		}
+	}
+
+	obj.SetFinalizers(removeString(obj.GetFinalizers(), FluentdConfigFinalizer))
+	return r.Update(ctx, obj)
+}
+
+// containsString checks whether a string slice contains the given string.
+func containsString(slice []string, s string) bool {
+	for _, item := range slice {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// removeString returns a copy of slice with every occurrence of s removed.
+func removeString(slice []string, s string) []string {
+	result := make([]string, 0, len(slice))
+	for _, item := range slice {
+		if item == s {
+			continue
+		}
+		result = append(result, item)
+	}
+	return result
+}
+
+// bindResources flattens the matched filter/output CRs for a cfg into the Binding entries
+// surfaced on its status, so `kubectl get fluentdconfig -o wide` shows what actually got wired
+// up instead of just a comma-joined error string.
+func bindResources(clusterfilters []fluentdv1alpha1.ClusterFilter, clusteroutputs []fluentdv1alpha1.ClusterOutput,
+	filters []fluentdv1alpha1.Filter, outputs []fluentdv1alpha1.Output) []fluentdv1alpha1.Binding {
+
+	bindings := make([]fluentdv1alpha1.Binding, 0, len(clusterfilters)+len(clusteroutputs)+len(filters)+len(outputs))
+
+	for _, f := range clusterfilters {
+		bindings = append(bindings, fluentdv1alpha1.Binding{
+			Kind:            "ClusterFilter",
+			Namespace:       f.Namespace,
+			Name:            f.Name,
+			UID:             f.UID,
+			ResourceVersion: f.ResourceVersion,
+			State:           "Bound",
+		})
+	}
+	for _, o := range clusteroutputs {
+		bindings = append(bindings, fluentdv1alpha1.Binding{
+			Kind:            "ClusterOutput",
+			Namespace:       o.Namespace,
+			Name:            o.Name,
+			UID:             o.UID,
+			ResourceVersion: o.ResourceVersion,
+			State:           "Bound",
+		})
+	}
+	for _, f := range filters {
+		bindings = append(bindings, fluentdv1alpha1.Binding{
+			Kind:            "Filter",
+			Namespace:       f.Namespace,
+			Name:            f.Name,
+			UID:             f.UID,
+			ResourceVersion: f.ResourceVersion,
+			State:           "Bound",
+		})
+	}
+	for _, o := range outputs {
+		bindings = append(bindings, fluentdv1alpha1.Binding{
+			Kind:            "Output",
+			Namespace:       o.Namespace,
+			Name:            o.Name,
+			UID:             o.UID,
+			ResourceVersion: o.ResourceVersion,
+			State:           "Bound",
+		})
+	}
+
+	return bindings
+}
+
+// attributeBindingFailures flips only the bindings a ResourceError actually names to Failed, by
+// exact Kind/Namespace/Name match against what PatchAndFilter*Resources reported - not by
+// searching for a binding's name inside a combined error string, which a short or overlapping
+// name could match spuriously. Errors that don't name a specific resource (e.g. an invalid
+// WorkerPlacement) still fail the cfg as a whole via the Ready/ConfigRendered conditions and the
+// legacy Errors field, without flipping any binding.
+func attributeBindingFailures(bindings []fluentdv1alpha1.Binding, resourceErrs []fluentdv1alpha1.ResourceError) {
+	for _, re := range resourceErrs {
+		for i := range bindings {
+			if bindings[i].Kind == re.Kind && bindings[i].Namespace == re.Namespace && bindings[i].Name == re.Name {
+				bindings[i].State = "Failed"
+				bindings[i].Message = re.Err.Error()
+			}
+		}
+	}
+}
+
+// PatchCfgStatus rolls the outcome of rendering a single cfg into its status subresource:
+// ObservedGeneration, the Bindings resolved this pass, and the Ready/ConfigRendered conditions.
+// resourceErrs attributes failures to the specific bindings that caused them; errs is the full
+// set of per-cfg errors (resourceErrs plus anything that isn't tied to one resource, like an
+// invalid WorkerPlacement) kept comma-joined in the legacy Errors field for consumers that haven't
+// moved to Conditions/Bindings yet.
+func (r *FluentdConfigReconciler) PatchCfgStatus(ctx context.Context, obj client.Object, bindings []fluentdv1alpha1.Binding, resourceErrs []fluentdv1alpha1.ResourceError, errs []string) error {
+	joined := strings.Join(errs, ",")
+
+	status := metav1.ConditionTrue
+	reason := "Bound"
+	if len(errs) > 0 {
+		status = metav1.ConditionFalse
+		reason = "ResourceBindingFailed"
+		attributeBindingFailures(bindings, resourceErrs)
+	}
+
+	configRendered := metav1.Condition{Type: ConditionConfigRendered, Status: status, Reason: reason, Message: joined}
+	ready := metav1.Condition{Type: ConditionReady, Status: status, Reason: reason, Message: joined}
+
+	switch o := obj.(type) {
+	case *fluentdv1alpha1.ClusterFluentdConfig:
+		original := o.DeepCopy()
+		o.Status.Errors = joined
+		o.Status.ObservedGeneration = o.Generation
+		o.Status.Bindings = bindings
+		apimeta.SetStatusCondition(&o.Status.Conditions, configRendered)
+		apimeta.SetStatusCondition(&o.Status.Conditions, ready)
+		return r.Status().Patch(ctx, o, client.MergeFromWithOptions(original))
+	case *fluentdv1alpha1.FluentdConfig:
+		original := o.DeepCopy()
+		o.Status.Errors = joined
+		o.Status.ObservedGeneration = o.Generation
+		o.Status.Bindings = bindings
+		apimeta.SetStatusCondition(&o.Status.Conditions, configRendered)
+		apimeta.SetStatusCondition(&o.Status.Conditions, ready)
+		return r.Status().Patch(ctx, o, client.MergeFromWithOptions(original))
+	default:
+		return nil
+	}
+}
+
+// conditionUnchanged reports whether conditions already holds a condition of want.Type with the
+// same Status/Reason/Message, so callers can skip re-stamping a condition that hasn't moved.
+func conditionUnchanged(conditions []metav1.Condition, want metav1.Condition) bool {
+	existing := apimeta.FindStatusCondition(conditions, want.Type)
+	return existing != nil && existing.Status == want.Status && existing.Reason == want.Reason && existing.Message == want.Message
+}
+
+// patchFluentdStatus rolls up how many cfgs this fd matched/failed this pass, records the
+// secret it just rendered, and folds in the outcome of the reload that followed it. It only
+// writes when one of those fields actually moved: fd is watched with no status-update predicate,
+// so an unconditional LastRenderedAt stamp here would make every Patch produce a non-empty diff,
+// which re-triggers Reconcile and stamps it again - a permanent reconcile storm across every fd.
+func (r *FluentdConfigReconciler) patchFluentdStatus(ctx context.Context, fd *fluentdv1alpha1.Fluentd, secName string, summary cfgSummary, reload reloadResult) error {
+	original := fd.DeepCopy()
+
+	status := metav1.ConditionTrue
+	reason := "ConfigsRendered"
+	if summary.failed > 0 {
+		status = metav1.ConditionFalse
+		reason = "ConfigsFailed"
+	}
+	ready := metav1.Condition{Type: ConditionReady, Status: status, Reason: reason}
+
+	publishedStatus := metav1.ConditionTrue
+	publishedReason := string(reload.strategy)
+	var publishedMessage string
+	switch reload.strategy {
+	case ReloadStrategyNone:
+		publishedMessage = "no reload requested"
+	case ReloadStrategyRestart:
+		// Bumping the StatefulSet's restart annotation only tells its controller to start rolling
+		// pods - it says nothing about whether that rollout actually succeeds, so this can't be
+		// reported as "N pod(s) reloaded" the way a graceful reload's RPC results can.
+		publishedMessage = "StatefulSet rollout restart triggered; pod-level outcome not tracked by this controller"
+	default:
+		publishedMessage = fmt.Sprintf("reload strategy %s: %d pod(s) reloaded, %d failed", reload.strategy, reload.succeeded, reload.failed)
+		if reload.failed > 0 {
+			publishedStatus = metav1.ConditionFalse
+		}
+	}
+	published := metav1.Condition{Type: ConditionSecretPublished, Status: publishedStatus, Reason: publishedReason, Message: publishedMessage}
+
+	if fd.Status.MatchedConfigs == int32(summary.matched) &&
+		fd.Status.FailedConfigs == int32(summary.failed) &&
+		fd.Status.SecretName == secName &&
+		conditionUnchanged(fd.Status.Conditions, ready) &&
+		conditionUnchanged(fd.Status.Conditions, published) {
+		return nil
+	}
+
+	fd.Status.ObservedGeneration = fd.Generation
+	fd.Status.MatchedConfigs = int32(summary.matched)
+	fd.Status.FailedConfigs = int32(summary.failed)
+	fd.Status.SecretName = secName
+	fd.Status.LastRenderedAt = metav1.Now()
+	apimeta.SetStatusCondition(&fd.Status.Conditions, ready)
+	apimeta.SetStatusCondition(&fd.Status.Conditions, published)
+
+	return r.Status().Patch(ctx, fd, client.MergeFromWithOptions(original))
+}
+
+// reloadResult tallies how many of a Fluentd's pods picked up a config reload, for status
+// reporting back on the Fluentd's SecretPublished condition.
+type reloadResult struct {
+	strategy  string
+	succeeded int
+	failed    int
+}
+
+// resolveReloadStrategy decides how fd should be reloaded after its secret changes: "None" when
+// HotReload is explicitly disabled, otherwise fd.Spec.ReloadStrategy (defaulting to
+// GracefulReload when unset).
+func resolveReloadStrategy(fd *fluentdv1alpha1.Fluentd) string {
+	hotReload := fd.Spec.HotReload == nil || *fd.Spec.HotReload
+	if !hotReload {
+		return ReloadStrategyNone
+	}
+
+	strategy := fd.Spec.ReloadStrategy
+	if strategy == "" {
+		strategy = ReloadStrategyGracefulReload
+	}
+	return strategy
+}
+
+// reloadFluentd asks every Ready pod owned by fd to hot-reload its config through fluentd's
+// built-in RPC endpoint, or rolls the StatefulSet instead when configured to do so. It is a
+// no-op when HotReload is disabled or the strategy is explicitly None.
+func (r *FluentdConfigReconciler) reloadFluentd(ctx context.Context, fd *fluentdv1alpha1.Fluentd) (reloadResult, error) {
+	strategy := resolveReloadStrategy(fd)
+
+	if strategy == ReloadStrategyNone {
+		return reloadResult{strategy: ReloadStrategyNone}, nil
+	}
+
+	if strategy == ReloadStrategyRestart {
+		return r.restartFluentd(ctx, fd)
+	}
+
+	var podList corev1.PodList
+	if err := r.List(ctx, &podList, client.InNamespace(fd.Namespace), client.MatchingFields{fluentdOwnerKey: fd.Name}); err != nil {
+		return reloadResult{strategy: strategy}, err
+	}
+
+	result := reloadResult{strategy: strategy}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	sem := make(chan struct{}, reloadWorkerPoolSize)
+
+	for i := range podList.Items {
+		pod := podList.Items[i]
+		if !podIsReady(&pod) || pod.Status.PodIP == "" {
+			continue
+		}
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			ok := r.gracefulReloadPod(ctx, &pod)
+
+			mu.Lock()
+			if ok {
+				result.succeeded++
+			} else {
+				result.failed++
+			}
+			mu.Unlock()
+		}()
+	}
+
+	wg.Wait()
+
+	return result, nil
+}
+
+// gracefulReloadPod calls fluentd's RPC endpoint to make a single pod re-read its config,
+// returning false on any transport error or non-2xx response.
+func (r *FluentdConfigReconciler) gracefulReloadPod(ctx context.Context, pod *corev1.Pod) bool {
+	reloadCtx, cancel := context.WithTimeout(ctx, reloadPodTimeout)
+	defer cancel()
+
+	url := fmt.Sprintf("http://%s:%d%s", pod.Status.PodIP, FluentdRPCPort, FluentdReloadPath)
+	req, err := http.NewRequestWithContext(reloadCtx, http.MethodGet, url, nil)
+	if err != nil {
+		r.Log.Error(err, "failed to build fluentd reload request", "pod", pod.Name)
+		return false
+	}
+
+	resp, err := r.httpClient().Do(req)
+	if err != nil {
+		r.Log.Error(err, "fluentd config reload request failed", "pod", pod.Name)
+		return false
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		r.Log.Info("fluentd config reload returned a non-2xx status", "pod", pod.Name, "status", resp.StatusCode)
+		return false
+	}
+
+	return true
+}
+
+// restartFluentd rolls the Fluentd StatefulSet instead of hitting the RPC endpoint, for plugin
+// changes that need a full process restart to take effect. Bumping the restart annotation only
+// tells the StatefulSet controller to start rolling pods - it has no visibility into whether that
+// rollout actually succeeds - so unlike gracefulReloadPod this doesn't report a succeeded count.
+func (r *FluentdConfigReconciler) restartFluentd(ctx context.Context, fd *fluentdv1alpha1.Fluentd) (reloadResult, error) {
+	var sts appsv1.StatefulSet
+	if err := r.Get(ctx, client.ObjectKey{Namespace: fd.Namespace, Name: fd.Name}, &sts); err != nil {
+		return reloadResult{strategy: ReloadStrategyRestart}, client.IgnoreNotFound(err)
+	}
+
+	if sts.Spec.Template.Annotations == nil {
+		sts.Spec.Template.Annotations = map[string]string{}
+	}
+	sts.Spec.Template.Annotations["fluentd.fluent.io/restartedAt"] = metav1.Now().Format(time.RFC3339)
+
+	if err := r.Update(ctx, &sts); err != nil {
+		return reloadResult{strategy: ReloadStrategyRestart}, err
+	}
+
+	return reloadResult{strategy: ReloadStrategyRestart}, nil
+}
+
+// podIsReady reports whether pod's Ready condition is currently true.
+func podIsReady(pod *corev1.Pod) bool {
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == corev1.PodReady {
+			return cond.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// httpClient returns the client used to issue reload RPCs, defaulting to http.DefaultClient.
+func (r *FluentdConfigReconciler) httpClient() *http.Client {
+	if r.HTTPClient != nil {
+		return r.HTTPClient
+	}
+	return http.DefaultClient
+}
+
 // PatchObjectErrors patches the errors to the obj
 func (r *FluentdConfigReconciler) PatchObjectErrors(ctx context.Context, obj client.Object, errs string) error {
 	switch o := obj.(type) {
 	case *fluentdv1alpha1.ClusterFluentdConfig:
+		original := o.DeepCopy()
 		o.Status.Errors = errs
-		err := r.Status().Patch(ctx, o, client.MergeFromWithOptions(o))
+		err := r.Status().Patch(ctx, o, client.MergeFromWithOptions(original))
 		if err != nil {
 			return err
 		}
 	case *fluentdv1alpha1.FluentdConfig:
+		original := o.DeepCopy()
 		o.Status.Errors = errs
-		err := r.Status().Patch(ctx, o, client.MergeFromWithOptions(o))
+		err := r.Status().Patch(ctx, o, client.MergeFromWithOptions(original))
 		if err != nil {
 			return err
 		}
 	case *fluentdv1alpha1.Fluentd:
+		original := o.DeepCopy()
 		o.Status.Errors = errs
-		err := r.Status().Patch(ctx, o, client.MergeFromWithOptions(o))
+		err := r.Status().Patch(ctx, o, client.MergeFromWithOptions(original))
 		if err != nil {
 			return err
 		}
@@ -441,14 +1161,35 @@ func (r *FluentdConfigReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		return err
 	}
 
+	// Indexed the same way as the ServiceAccount above, so reloadFluentd can look up a fd's pods
+	// with a single List instead of walking the StatefulSet.
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &corev1.Pod{}, fluentdOwnerKey, func(rawObj client.Object) []string {
+		pod := rawObj.(*corev1.Pod)
+		owner := metav1.GetControllerOf(pod)
+		if owner == nil {
+			return nil
+		}
+		if owner.APIVersion != fluentdApiGVStr || owner.Kind != "Fluentd" {
+			return nil
+		}
+		return []string{owner.Name}
+	}); err != nil {
+		return err
+	}
+
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&fluentdv1alpha1.Fluentd{}).
 		Owns(&corev1.Secret{}).
+		Owns(&corev1.Pod{}).
 		Watches(&source.Kind{Type: &fluentdv1alpha1.ClusterFluentdConfig{}}, &handler.EnqueueRequestForObject{}).
 		Watches(&source.Kind{Type: &fluentdv1alpha1.FluentdConfig{}}, &handler.EnqueueRequestForObject{}).
 		Watches(&source.Kind{Type: &fluentdv1alpha1.Filter{}}, &handler.EnqueueRequestForObject{}).
 		Watches(&source.Kind{Type: &fluentdv1alpha1.ClusterFilter{}}, &handler.EnqueueRequestForObject{}).
 		Watches(&source.Kind{Type: &fluentdv1alpha1.Output{}}, &handler.EnqueueRequestForObject{}).
 		Watches(&source.Kind{Type: &fluentdv1alpha1.ClusterOutput{}}, &handler.EnqueueRequestForObject{}).
+		// A namespace being created or relabeled can change which namespaces a
+		// WatchedNamespaceSelector/ExcludedNamespaceSelector resolves to, so every Fluentd needs
+		// a chance to re-render.
+		Watches(&source.Kind{Type: &corev1.Namespace{}}, &handler.EnqueueRequestForObject{}).
 		Complete(r)
 }
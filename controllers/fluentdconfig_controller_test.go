@@ -0,0 +1,432 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	fluentdv1alpha1 "fluent.io/fluent-operator/apis/fluentd/v1alpha1"
+)
+
+func boolPtr(b bool) *bool { return &b }
+
+func TestResolveReloadStrategy_DefaultsToGracefulReload(t *testing.T) {
+	fd := &fluentdv1alpha1.Fluentd{}
+
+	if got := resolveReloadStrategy(fd); got != ReloadStrategyGracefulReload {
+		t.Fatalf("got %q, want %q", got, ReloadStrategyGracefulReload)
+	}
+}
+
+func TestResolveReloadStrategy_HotReloadDisabledWinsOverExplicitStrategy(t *testing.T) {
+	fd := &fluentdv1alpha1.Fluentd{}
+	fd.Spec.HotReload = boolPtr(false)
+	fd.Spec.ReloadStrategy = ReloadStrategyRestart
+
+	if got := resolveReloadStrategy(fd); got != ReloadStrategyNone {
+		t.Fatalf("got %q, want %q", got, ReloadStrategyNone)
+	}
+}
+
+func TestResolveReloadStrategy_ExplicitNone(t *testing.T) {
+	fd := &fluentdv1alpha1.Fluentd{}
+	fd.Spec.HotReload = boolPtr(true)
+	fd.Spec.ReloadStrategy = ReloadStrategyNone
+
+	if got := resolveReloadStrategy(fd); got != ReloadStrategyNone {
+		t.Fatalf("got %q, want %q", got, ReloadStrategyNone)
+	}
+}
+
+func TestResolveReloadStrategy_ExplicitRestart(t *testing.T) {
+	fd := &fluentdv1alpha1.Fluentd{}
+	fd.Spec.HotReload = boolPtr(true)
+	fd.Spec.ReloadStrategy = ReloadStrategyRestart
+
+	if got := resolveReloadStrategy(fd); got != ReloadStrategyRestart {
+		t.Fatalf("got %q, want %q", got, ReloadStrategyRestart)
+	}
+}
+
+func TestRestartFluentd_DoesNotClaimPodSuccessCounts(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("failed to build scheme: %v", err)
+	}
+	if err := appsv1.AddToScheme(scheme); err != nil {
+		t.Fatalf("failed to build scheme: %v", err)
+	}
+
+	fd := &fluentdv1alpha1.Fluentd{ObjectMeta: metav1.ObjectMeta{Name: "fd1", Namespace: "ns"}}
+	sts := &appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Name: "fd1", Namespace: "ns"}}
+
+	r := &FluentdConfigReconciler{
+		Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(sts).Build(),
+	}
+
+	result, err := r.restartFluentd(context.Background(), fd)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.strategy != ReloadStrategyRestart {
+		t.Fatalf("got strategy %q, want %q", result.strategy, ReloadStrategyRestart)
+	}
+	if result.succeeded != 0 || result.failed != 0 {
+		t.Fatalf("got succeeded=%d failed=%d, want 0/0: a rollout-restart annotation bump doesn't confirm any pod actually reloaded", result.succeeded, result.failed)
+	}
+
+	var got appsv1.StatefulSet
+	if err := r.Get(context.Background(), client.ObjectKey{Namespace: "ns", Name: "fd1"}, &got); err != nil {
+		t.Fatalf("unexpected error reading back statefulset: %v", err)
+	}
+	if got.Spec.Template.Annotations["fluentd.fluent.io/restartedAt"] == "" {
+		t.Fatal("expected the restartedAt annotation to be set on the pod template")
+	}
+}
+
+func TestNextWorkerAssignment_NilPlacementRoundRobins(t *testing.T) {
+	pass := &renderPass{totalWorkers: 3}
+
+	for i, want := range []int32{0, 1, 2, 0} {
+		got, err := pass.nextWorkerAssignment(nil)
+		if err != nil {
+			t.Fatalf("iteration %d: unexpected error: %v", i, err)
+		}
+		if len(got) != 1 || got[0] != want {
+			t.Fatalf("iteration %d: got %v, want [%d]", i, got, want)
+		}
+	}
+}
+
+func TestNextWorkerAssignment_SingleWorkerRoundRobin(t *testing.T) {
+	pass := &renderPass{totalWorkers: 1}
+
+	got, err := pass.nextWorkerAssignment(nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 1 || got[0] != 0 {
+		t.Fatalf("got %v, want [0]", got)
+	}
+}
+
+func TestNextWorkerAssignment_PinnedWorkers(t *testing.T) {
+	pass := &renderPass{totalWorkers: 4}
+	placement := &fluentdv1alpha1.WorkerPlacement{Workers: []int32{1, 3}}
+
+	got, err := pass.nextWorkerAssignment(placement)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 2 || got[0] != 1 || got[1] != 3 {
+		t.Fatalf("got %v, want [1 3]", got)
+	}
+}
+
+func TestNextWorkerAssignment_PinnedWorkerOutOfRangeFallsBackToRoundRobin(t *testing.T) {
+	pass := &renderPass{totalWorkers: 2}
+	placement := &fluentdv1alpha1.WorkerPlacement{Workers: []int32{5}}
+
+	got, err := pass.nextWorkerAssignment(placement)
+	if err == nil {
+		t.Fatal("expected an out-of-range error, got nil")
+	}
+	if len(got) != 1 || got[0] != 0 {
+		t.Fatalf("got %v, want round-robin fallback [0]", got)
+	}
+}
+
+func TestNextWorkerAssignment_Shard(t *testing.T) {
+	pass := &renderPass{totalWorkers: 6}
+	placement := &fluentdv1alpha1.WorkerPlacement{
+		Shard: &fluentdv1alpha1.WorkerShard{Replicas: 2, Ordinal: 1},
+	}
+
+	got, err := pass.nextWorkerAssignment(placement)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []int32{1, 3, 5}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+	}
+}
+
+func TestNextWorkerAssignment_ShardOrdinalOutOfRangeFallsBackToRoundRobin(t *testing.T) {
+	pass := &renderPass{totalWorkers: 3}
+	placement := &fluentdv1alpha1.WorkerPlacement{
+		Shard: &fluentdv1alpha1.WorkerShard{Replicas: 2, Ordinal: 2},
+	}
+
+	got, err := pass.nextWorkerAssignment(placement)
+	if err == nil {
+		t.Fatal("expected an out-of-range ordinal error, got nil")
+	}
+	if len(got) != 1 || got[0] != 0 {
+		t.Fatalf("got %v, want round-robin fallback [0]", got)
+	}
+}
+
+func TestNextWorkerAssignment_ShardMatchesNoWorkersFallsBackToRoundRobin(t *testing.T) {
+	pass := &renderPass{totalWorkers: 2}
+	placement := &fluentdv1alpha1.WorkerPlacement{
+		Shard: &fluentdv1alpha1.WorkerShard{Replicas: 5, Ordinal: 4},
+	}
+
+	got, err := pass.nextWorkerAssignment(placement)
+	if err == nil {
+		t.Fatal("expected a no-workers-matched error, got nil")
+	}
+	if len(got) != 1 || got[0] != 0 {
+		t.Fatalf("got %v, want round-robin fallback [0]", got)
+	}
+}
+
+func newNamespace(name string, labels map[string]string) *corev1.Namespace {
+	return &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels},
+	}
+}
+
+func newReconcilerWithNamespaces(t *testing.T, namespaces ...*corev1.Namespace) *FluentdConfigReconciler {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("failed to build scheme: %v", err)
+	}
+
+	objs := make([]client.Object, 0, len(namespaces))
+	for _, ns := range namespaces {
+		objs = append(objs, ns)
+	}
+
+	return &FluentdConfigReconciler{
+		Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build(),
+	}
+}
+
+func TestResolveWatchedNamespaces_NoSelectorsIsNoOp(t *testing.T) {
+	r := newReconcilerWithNamespaces(t)
+
+	got, err := r.resolveWatchedNamespaces(context.Background(), []string{"a", "b"}, nil, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 2 || got[0] != "a" || got[1] != "b" {
+		t.Fatalf("got %v, want [a b]", got)
+	}
+}
+
+func TestResolveWatchedNamespaces_WatchedSelectorIntersectsExplicitList(t *testing.T) {
+	r := newReconcilerWithNamespaces(t,
+		newNamespace("a", map[string]string{"team": "logging"}),
+		newNamespace("b", map[string]string{"team": "other"}),
+		newNamespace("c", map[string]string{"team": "logging"}),
+	)
+	selector := &metav1.LabelSelector{MatchLabels: map[string]string{"team": "logging"}}
+
+	got, err := r.resolveWatchedNamespaces(context.Background(), []string{"a", "b"}, selector, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 1 || got[0] != "a" {
+		t.Fatalf("got %v, want [a] (b isn't in the selector match set, c wasn't in the explicit list)", got)
+	}
+}
+
+func TestResolveWatchedNamespaces_ExcludedSelectorSubtracts(t *testing.T) {
+	r := newReconcilerWithNamespaces(t,
+		newNamespace("a", map[string]string{"stage": "prod"}),
+		newNamespace("b", map[string]string{"stage": "dev"}),
+	)
+	excluded := &metav1.LabelSelector{MatchLabels: map[string]string{"stage": "dev"}}
+
+	got, err := r.resolveWatchedNamespaces(context.Background(), []string{"a", "b"}, nil, excluded)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 1 || got[0] != "a" {
+		t.Fatalf("got %v, want [a]", got)
+	}
+}
+
+func TestResolveWatchedNamespaces_WatchedAndExcludedSelectorsCombine(t *testing.T) {
+	r := newReconcilerWithNamespaces(t,
+		newNamespace("a", map[string]string{"team": "logging", "stage": "prod"}),
+		newNamespace("b", map[string]string{"team": "logging", "stage": "dev"}),
+		newNamespace("c", map[string]string{"team": "other", "stage": "prod"}),
+	)
+	watched := &metav1.LabelSelector{MatchLabels: map[string]string{"team": "logging"}}
+	excluded := &metav1.LabelSelector{MatchLabels: map[string]string{"stage": "dev"}}
+
+	got, err := r.resolveWatchedNamespaces(context.Background(), []string{"a", "b", "c"}, watched, excluded)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 1 || got[0] != "a" {
+		t.Fatalf("got %v, want [a]", got)
+	}
+}
+
+func TestBindResources_FlattensAllKindsAsBound(t *testing.T) {
+	clusterFilters := []fluentdv1alpha1.ClusterFilter{{ObjectMeta: metav1.ObjectMeta{Name: "cf1"}}}
+	clusterOutputs := []fluentdv1alpha1.ClusterOutput{{ObjectMeta: metav1.ObjectMeta{Name: "co1"}}}
+	filters := []fluentdv1alpha1.Filter{{ObjectMeta: metav1.ObjectMeta{Name: "f1", Namespace: "ns"}}}
+	outputs := []fluentdv1alpha1.Output{{ObjectMeta: metav1.ObjectMeta{Name: "o1", Namespace: "ns"}}}
+
+	bindings := bindResources(clusterFilters, clusterOutputs, filters, outputs)
+
+	if len(bindings) != 4 {
+		t.Fatalf("got %d bindings, want 4", len(bindings))
+	}
+	for _, b := range bindings {
+		if b.State != "Bound" {
+			t.Errorf("binding %s/%s: got state %q, want Bound", b.Namespace, b.Name, b.State)
+		}
+	}
+}
+
+func TestAttributeBindingFailures_OnlyFlipsMatchedBindings(t *testing.T) {
+	bindings := []fluentdv1alpha1.Binding{
+		{Kind: "Filter", Name: "f1", State: "Bound"},
+		{Kind: "Output", Name: "o1", State: "Bound"},
+	}
+	resourceErrs := []fluentdv1alpha1.ResourceError{
+		{Kind: "Output", Name: "o1", Err: errors.New("connection refused")},
+	}
+
+	attributeBindingFailures(bindings, resourceErrs)
+
+	if bindings[0].State != "Bound" {
+		t.Errorf("f1 state = %q, want Bound (no error referenced it)", bindings[0].State)
+	}
+	if bindings[1].State != "Failed" {
+		t.Errorf("o1 state = %q, want Failed", bindings[1].State)
+	}
+	if bindings[1].Message != resourceErrs[0].Err.Error() {
+		t.Errorf("o1 message = %q, want %q", bindings[1].Message, resourceErrs[0].Err.Error())
+	}
+}
+
+func TestAttributeBindingFailures_DoesNotMatchOnNameSubstring(t *testing.T) {
+	bindings := []fluentdv1alpha1.Binding{
+		{Kind: "Output", Name: "es", State: "Bound"},
+		{Kind: "Output", Name: "es-backup", State: "Bound"},
+	}
+	resourceErrs := []fluentdv1alpha1.ResourceError{
+		{Kind: "Output", Name: "es-backup", Err: errors.New("connection refused")},
+	}
+
+	attributeBindingFailures(bindings, resourceErrs)
+
+	if bindings[0].State != "Bound" {
+		t.Errorf(`"es" state = %q, want Bound (only "es-backup" failed, "es" is not a substring match)`, bindings[0].State)
+	}
+	if bindings[1].State != "Failed" {
+		t.Errorf(`"es-backup" state = %q, want Failed`, bindings[1].State)
+	}
+}
+
+func newReconcilerWithCfgScheme(t *testing.T, objs ...client.Object) *FluentdConfigReconciler {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("failed to build scheme: %v", err)
+	}
+	if err := fluentdv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatalf("failed to build scheme: %v", err)
+	}
+
+	return &FluentdConfigReconciler{
+		Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build(),
+	}
+}
+
+func TestSweepOrphanedCfgs_UnblocksCfgNoLongerMatchedByAnyLiveFluentd(t *testing.T) {
+	cfg := &fluentdv1alpha1.FluentdConfig{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "cfg1",
+			Namespace:  "ns",
+			Labels:     map[string]string{"team": "logging"},
+			Finalizers: []string{FluentdConfigFinalizer},
+		},
+	}
+	r := newReconcilerWithCfgScheme(t, cfg)
+
+	if err := r.Delete(context.Background(), cfg); err != nil {
+		t.Fatalf("failed to mark cfg for deletion: %v", err)
+	}
+
+	// No live fd selects anything, so cfg's labels can't match any selector - it's orphaned.
+	if err := r.sweepOrphanedCfgs(context.Background(), nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var got fluentdv1alpha1.FluentdConfig
+	err := r.Get(context.Background(), client.ObjectKey{Namespace: "ns", Name: "cfg1"}, &got)
+	if !apierrors.IsNotFound(err) {
+		t.Fatalf("expected cfg1 to be fully deleted once its finalizer was dropped, got err=%v obj=%+v", err, got)
+	}
+}
+
+func TestSweepOrphanedCfgs_LeavesCfgStillMatchedByALiveFluentd(t *testing.T) {
+	cfg := &fluentdv1alpha1.FluentdConfig{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "cfg1",
+			Namespace:  "ns",
+			Labels:     map[string]string{"team": "logging"},
+			Finalizers: []string{FluentdConfigFinalizer},
+		},
+	}
+	r := newReconcilerWithCfgScheme(t, cfg)
+
+	if err := r.Delete(context.Background(), cfg); err != nil {
+		t.Fatalf("failed to mark cfg for deletion: %v", err)
+	}
+
+	liveFd := fluentdv1alpha1.Fluentd{ObjectMeta: metav1.ObjectMeta{Name: "fd1", Namespace: "ns"}}
+	liveFd.Spec.FluentdCfgSelector = metav1.LabelSelector{MatchLabels: map[string]string{"team": "logging"}}
+
+	// cfg is still matched by fd1's selector, so the per-fd Reconcile loop - not this sweep - owns
+	// cleaning it up once fd1's secret has actually been re-rendered without it.
+	if err := r.sweepOrphanedCfgs(context.Background(), []fluentdv1alpha1.Fluentd{liveFd}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var got fluentdv1alpha1.FluentdConfig
+	if err := r.Get(context.Background(), client.ObjectKey{Namespace: "ns", Name: "cfg1"}, &got); err != nil {
+		t.Fatalf("expected cfg1 to still exist untouched, got err=%v", err)
+	}
+	if !containsString(got.GetFinalizers(), FluentdConfigFinalizer) {
+		t.Fatal("expected cfg1 to keep its finalizer - the sweep must not touch cfgs still matched by a live fluentd")
+	}
+}